@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientConfig configures the *http.Transport built for a particular host
+type ClientConfig struct {
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ExpectContinueTimeout time.Duration
+	MaxIdleConnsPerHost   int
+	IdleConnTimeout       time.Duration
+	RequestTimeout        time.Duration
+	ForceAttemptHTTP2     bool
+
+	// InsecureSkipVerify disables certificate validation entirely, used by GetInsecureHTTPClient
+	InsecureSkipVerify bool
+
+	// RootCAs, Certificates and ServerName let a channel verify against a private CA or present a
+	// client certificate for mTLS
+	RootCAs      *x509.CertPool
+	Certificates []tls.Certificate
+	ServerName   string
+
+	// PinnedSPKIHash, when set, is the base64 encoded SHA-256 hash of the expected certificate's
+	// SubjectPublicKeyInfo. If no certificate presented by the server matches, the handshake is
+	// rejected. This enables trust-on-first-use style pinning for channels that can't rely on the
+	// normal certificate authority chain.
+	PinnedSPKIHash string
+}
+
+// DefaultClientConfig returns the ClientConfig used for any host without its own configuration set via
+// SetClientConfig
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		DialTimeout:           10 * time.Second,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       30 * time.Second,
+		RequestTimeout:        30 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+// ClientPool hands out a *http.Client per host, each built from its own ClientConfig, so a channel that
+// needs more idle connections, a private CA or a longer handshake timeout doesn't have to share a
+// transport with every other host Courier talks to
+type ClientPool struct {
+	mutex         sync.Mutex
+	clients       map[string]*http.Client
+	configs       map[string]ClientConfig
+	defaultConfig ClientConfig
+}
+
+// NewClientPool creates a ClientPool that falls back to defaultConfig for any host without a config of
+// its own
+func NewClientPool(defaultConfig ClientConfig) *ClientPool {
+	return &ClientPool{
+		clients:       make(map[string]*http.Client),
+		configs:       make(map[string]ClientConfig),
+		defaultConfig: defaultConfig,
+	}
+}
+
+// SetClientConfig sets the ClientConfig to use for host, discarding any client already cached for it so
+// the new configuration takes effect on the next request
+func (p *ClientPool) SetClientConfig(host string, config ClientConfig) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.configs[host] = config
+	delete(p.clients, clientKey(host, false))
+	delete(p.clients, clientKey(host, true))
+}
+
+// ClientFor returns the *http.Client to use for host, building and caching one the first time it's
+// requested. insecure selects a transport that skips certificate validation.
+func (p *ClientPool) ClientFor(host string, insecure bool) *http.Client {
+	key := clientKey(host, insecure)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if client, found := p.clients[key]; found {
+		return client
+	}
+
+	config, found := p.configs[host]
+	if !found {
+		config = p.defaultConfig
+	}
+	if insecure {
+		config.InsecureSkipVerify = true
+	}
+
+	client := newClientFromConfig(config)
+	p.clients[key] = client
+	return client
+}
+
+func clientKey(host string, insecure bool) string {
+	if insecure {
+		return host + "|insecure"
+	}
+	return host
+}
+
+func newClientFromConfig(config ClientConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		RootCAs:            config.RootCAs,
+		Certificates:       config.Certificates,
+		ServerName:         config.ServerName,
+	}
+
+	if config.PinnedSPKIHash != "" {
+		// we take over verification entirely so a stale or wrong CA configuration can't mask a
+		// mismatched pin
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifySPKIPin(config.PinnedSPKIHash)
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ExpectContinueTimeout: config.ExpectContinueTimeout,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		ForceAttemptHTTP2:     config.ForceAttemptHTTP2,
+		TLSClientConfig:       tlsConfig,
+	}
+
+	return &http.Client{Transport: transport, Timeout: config.RequestTimeout}
+}
+
+// verifySPKIPin returns a tls.Config.VerifyPeerCertificate callback that accepts the connection only if
+// one of the presented certificates' SubjectPublicKeyInfo hashes to expected
+func verifySPKIPin(expected string) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for _, rawCert := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				continue
+			}
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if base64.StdEncoding.EncodeToString(sum[:]) == expected {
+				return nil
+			}
+		}
+		return fmt.Errorf("no certificate matched pinned SPKI hash %s", expected)
+	}
+}
+
+var defaultPool = NewClientPool(DefaultClientConfig())
+
+// GetHTTPClient returns the shared HTTP client used for requests to host, built from any ClientConfig
+// set for it via SetClientConfig or from DefaultClientConfig otherwise
+func GetHTTPClient(host string) *http.Client {
+	return defaultPool.ClientFor(host, false)
+}
+
+// GetInsecureHTTPClient is the insecure-transport equivalent of GetHTTPClient, used by channels that
+// talk to endpoints with self-signed or otherwise unvalidated certificates
+func GetInsecureHTTPClient(host string) *http.Client {
+	return defaultPool.ClientFor(host, true)
+}
+
+// SetClientConfig overrides DefaultClientConfig for host, letting operators tune connection reuse and
+// TLS settings per channel without affecting every other host Courier talks to
+func SetClientConfig(host string, config ClientConfig) {
+	defaultPool.SetClientConfig(host, config)
+}
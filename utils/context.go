@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+)
+
+// MakeHTTPRequestWithContext is the context-aware equivalent of MakeHTTPRequest. ctx is attached to req
+// before it's fired, so the caller can cancel the request - for example when a channel's worker is
+// shutting down - rather than blocking for the full client timeout.
+func MakeHTTPRequestWithContext(ctx context.Context, req *http.Request) (*RequestResponse, error) {
+	return MakeHTTPRequest(req.WithContext(ctx))
+}
+
+// MakeInsecureHTTPRequestWithContext is the context-aware equivalent of MakeInsecureHTTPRequest
+func MakeInsecureHTTPRequestWithContext(ctx context.Context, req *http.Request) (*RequestResponse, error) {
+	return MakeInsecureHTTPRequest(req.WithContext(ctx))
+}
+
+// MakeHTTPRequestWithContextAndRetries is the context-aware equivalent of MakeHTTPRequestWithRetries. In
+// addition to attaching ctx to req, it aborts between retry attempts as soon as ctx is done rather than
+// sleeping out the full backoff delay.
+func MakeHTTPRequestWithContextAndRetries(ctx context.Context, req *http.Request, policy RetryPolicy) (*RequestResponse, error) {
+	return MakeHTTPRequestWithRetries(req.WithContext(ctx), policy)
+}
+
+// MakeInsecureHTTPRequestWithContextAndRetries is the insecure-transport equivalent of
+// MakeHTTPRequestWithContextAndRetries
+func MakeInsecureHTTPRequestWithContextAndRetries(ctx context.Context, req *http.Request, policy RetryPolicy) (*RequestResponse, error) {
+	return MakeInsecureHTTPRequestWithRetries(req.WithContext(ctx), policy)
+}
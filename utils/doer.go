@@ -0,0 +1,42 @@
+package utils
+
+import "net/http"
+
+// HTTPDoer is implemented by anything that can fire an http.Request and produce a RequestResponse trace.
+// Handlers should depend on this interface rather than calling MakeHTTPRequest directly, so tests can
+// substitute a fake and production code can wrap a doer with middleware — request signing, OAuth2 token
+// injection, tracing, per-channel rate limiting and the like.
+type HTTPDoer interface {
+	Do(req *http.Request) (*RequestResponse, error)
+}
+
+// httpClient is the default HTTPDoer implementation, firing requests against a per-host pooled
+// *http.Client and retrying according to its RetryPolicy
+type httpClient struct {
+	insecure bool
+	retries  RetryPolicy
+}
+
+// NewHTTPClient returns an HTTPDoer that fires requests against real hosts, retrying according to policy
+func NewHTTPClient(policy RetryPolicy) HTTPDoer {
+	return &httpClient{retries: policy}
+}
+
+// NewInsecureHTTPClient is the insecure-transport equivalent of NewHTTPClient
+func NewInsecureHTTPClient(policy RetryPolicy) HTTPDoer {
+	return &httpClient{insecure: true, retries: policy}
+}
+
+func (c *httpClient) Do(req *http.Request) (*RequestResponse, error) {
+	client := GetHTTPClient(req.URL.Host)
+	if c.insecure {
+		client = GetInsecureHTTPClient(req.URL.Host)
+	}
+	return makeHTTPRequestWithRetries(req, c.retries, client)
+}
+
+// DefaultHTTPClient is the HTTPDoer used by the package-level MakeHTTPRequest helper
+var DefaultHTTPClient HTTPDoer = NewHTTPClient(RetryPolicy{MaxAttempts: 1})
+
+// DefaultInsecureHTTPClient is the HTTPDoer used by the package-level MakeInsecureHTTPRequest helper
+var DefaultInsecureHTTPClient HTTPDoer = NewInsecureHTTPClient(RetryPolicy{MaxAttempts: 1})
@@ -1,13 +1,13 @@
 package utils
 
 import (
-	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/http/httputil"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -22,9 +22,42 @@ type RequestResponse struct {
 	Request    string
 	Response   string
 	Body       []byte
+	Truncated  bool
 	Elapsed    time.Duration
 }
 
+// MaxBodyBytes caps how many bytes of a textual response body are read into a RequestResponse trace,
+// guarding against misconfigured endpoints that return huge payloads or open-ended streams such as
+// text/event-stream. Operators can lower or raise this globally to suit their webhook endpoints.
+var MaxBodyBytes int64 = 1 << 20 // 1 MiB
+
+// textualContentTypes are the media types whose body is safe to read into a RequestResponse trace
+var textualContentTypes = map[string]bool{
+	"text/plain":                        true,
+	"text/html":                         true,
+	"text/xml":                          true,
+	"text/csv":                          true,
+	"application/json":                  true,
+	"application/xml":                   true,
+	"application/javascript":            true,
+	"application/x-www-form-urlencoded": true,
+}
+
+// isTextualContentType returns whether contentType is safe to read into memory as part of a
+// RequestResponse trace
+func isTextualContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+
+	return textualContentTypes[mediaType] || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
 const (
 	// RRStatusSuccess represents that the webhook was successful
 	RRStatusSuccess RequestResponseStatus = "S"
@@ -39,53 +72,21 @@ const (
 // MakeInsecureHTTPRequest fires the passed in http request against a transport that does not validate
 // SSL certificates.
 func MakeInsecureHTTPRequest(req *http.Request) (*RequestResponse, error) {
-	start := time.Now()
-	requestTrace, err := httputil.DumpRequestOut(req, true)
-	if err != nil {
-		rr, _ := newRRFromRequestAndError(req, string(requestTrace), err)
-		return rr, err
-	}
-
-	resp, err := GetInsecureHTTPClient().Do(req)
-	if err != nil {
-		rr, _ := newRRFromRequestAndError(req, string(requestTrace), err)
-		return rr, err
-	}
-	defer resp.Body.Close()
-
-	rr, err := newRRFromResponse(string(requestTrace), resp)
-	rr.Elapsed = time.Now().Sub(start)
-	return rr, err
+	return DefaultInsecureHTTPClient.Do(req)
 }
 
 // MakeHTTPRequest fires the passed in http request, returning any errors encountered. RequestResponse is always set
 // regardless of any errors being set
 func MakeHTTPRequest(req *http.Request) (*RequestResponse, error) {
-	start := time.Now()
-	requestTrace, err := httputil.DumpRequestOut(req, true)
-	if err != nil {
-		rr, _ := newRRFromRequestAndError(req, string(requestTrace), err)
-		return rr, err
-	}
-
-	resp, err := GetHTTPClient().Do(req)
-	if err != nil {
-		rr, _ := newRRFromRequestAndError(req, string(requestTrace), err)
-		return rr, err
-	}
-	defer resp.Body.Close()
-
-	rr, err := newRRFromResponse(string(requestTrace), resp)
-	rr.Elapsed = time.Now().Sub(start)
-	return rr, err
+	return DefaultHTTPClient.Do(req)
 }
 
 // newRRFromResponse creates a new RequestResponse based on the passed in http request and error (when we received no response)
 func newRRFromRequestAndError(r *http.Request, requestTrace string, requestError error) (*RequestResponse, error) {
 	rr := RequestResponse{}
-	rr.URL = r.URL.String()
+	rr.URL = redactURL(r.URL.String(), Redaction.QueryParams)
 
-	rr.Request = requestTrace
+	rr.Request = redact(requestTrace, Redaction)
 	rr.Status = RRConnectionFailure
 	rr.Body = []byte(requestError.Error())
 
@@ -96,7 +97,7 @@ func newRRFromRequestAndError(r *http.Request, requestTrace string, requestError
 func newRRFromResponse(requestTrace string, r *http.Response) (*RequestResponse, error) {
 	var err error
 	rr := RequestResponse{}
-	rr.URL = r.Request.URL.String()
+	rr.URL = redactURL(r.Request.URL.String(), Redaction.QueryParams)
 	rr.StatusCode = r.StatusCode
 
 	// set our status based on our status code
@@ -106,33 +107,31 @@ func newRRFromResponse(requestTrace string, r *http.Response) (*RequestResponse,
 		rr.Status = RRStatusFailure
 	}
 
-	rr.Request = requestTrace
+	rr.Request = redact(requestTrace, Redaction)
 
-	// figure out if our Response is something that looks like text from our headers
-	isText := false
-	contentType := r.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text") ||
-		strings.Contains(contentType, "json") ||
-		strings.Contains(contentType, "utf") ||
-		strings.Contains(contentType, "javascript") ||
-		strings.Contains(contentType, "xml") {
-
-		isText = true
-	}
-
-	// only dump the whole body if this looks like text
-	response, err := httputil.DumpResponse(r, isText)
+	// dump status line and headers only, body (if any) is capped and appended separately below
+	headerDump, err := httputil.DumpResponse(r, false)
 	if err != nil {
 		return &rr, err
 	}
-	rr.Response = string(response)
+	rr.Response = redact(string(headerDump), Redaction)
 
-	if isText {
-		bodyBytes, err := ioutil.ReadAll(r.Body)
+	// only read the body into memory if it looks like text, capping it at MaxBodyBytes so a
+	// misconfigured endpoint returning a huge or open-ended payload can't exhaust memory
+	if isTextualContentType(r.Header.Get("Content-Type")) {
+		bodyBytes, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
 		if err != nil {
 			return &rr, err
 		}
-		rr.Body = bodyBytes
+
+		if int64(len(bodyBytes)) > MaxBodyBytes {
+			bodyBytes = bodyBytes[:MaxBodyBytes]
+			rr.Truncated = true
+		}
+
+		redactedBody := redact(string(bodyBytes), Redaction)
+		rr.Body = []byte(redactedBody)
+		rr.Response += redactedBody
 	}
 
 	// return an error if we got a non-200 status
@@ -142,38 +141,3 @@ func newRRFromResponse(requestTrace string, r *http.Response) (*RequestResponse,
 
 	return &rr, err
 }
-
-var (
-	transport *http.Transport
-	client    *http.Client
-	once      sync.Once
-)
-
-// GetHTTPClient returns the shared HTTP client used by all Courier threads
-func GetHTTPClient() *http.Client {
-	once.Do(func() {
-		timeout := time.Duration(30 * time.Second)
-		transport = &http.Transport{
-			MaxIdleConns:    10,
-			IdleConnTimeout: 30 * time.Second,
-		}
-		client = &http.Client{Transport: transport, Timeout: timeout}
-	})
-
-	return client
-}
-
-// GetInsecureHTTPClient returns the shared HTTP client used by all Courier threads
-func GetInsecureHTTPClient() *http.Client {
-	once.Do(func() {
-		timeout := time.Duration(30 * time.Second)
-		transport = &http.Transport{
-			MaxIdleConns:    10,
-			IdleConnTimeout: 30 * time.Second,
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		client = &http.Client{Transport: transport, Timeout: timeout}
-	})
-
-	return client
-}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsTextualContentType(t *testing.T) {
+	cases := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/plain", true},
+		{"text/html; charset=utf-8", true},
+		{"application/json", true},
+		{"application/vnd.api+json", true},
+		{"application/atom+xml", true},
+		{"application/x-www-form-urlencoded", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"", false},
+		{"not a media type;;;", false},
+	}
+
+	for _, c := range cases {
+		if got := isTextualContentType(c.contentType); got != c.want {
+			t.Errorf("isTextualContentType(%q) = %v, want %v", c.contentType, got, c.want)
+		}
+	}
+}
+
+func TestNewRRFromResponseTruncatesAtMaxBodyBytes(t *testing.T) {
+	originalMaxBodyBytes := MaxBodyBytes
+	defer func() { MaxBodyBytes = originalMaxBodyBytes }()
+
+	cases := []struct {
+		name          string
+		body          string
+		maxBodyBytes  int64
+		wantTruncated bool
+		wantBody      string
+	}{
+		{"exactly at cap", "abcd", 4, false, "abcd"},
+		{"one over cap", "abcde", 4, true, "abcd"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			MaxBodyBytes = c.maxBodyBytes
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte(c.body))
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			rr, err := MakeHTTPRequest(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rr.Truncated != c.wantTruncated {
+				t.Errorf("rr.Truncated = %v, want %v", rr.Truncated, c.wantTruncated)
+			}
+			if string(rr.Body) != c.wantBody {
+				t.Errorf("rr.Body = %q, want %q", rr.Body, c.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewRRFromResponseSkipsBinaryBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte{0x00, 0x01, 0x02})
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	rr, err := MakeHTTPRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rr.Body) != 0 {
+		t.Errorf("rr.Body = %v, want empty for a non-textual content type", rr.Body)
+	}
+	if rr.Truncated {
+		t.Error("rr.Truncated = true, want false when the body was never read")
+	}
+}
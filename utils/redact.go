@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// redactedValue replaces any header, query parameter or body field scrubbed from a RequestResponse trace
+const redactedValue = "********"
+
+// RedactionConfig lists the headers, query parameters and JSON body fields (matched by name at any
+// nesting depth) that should be scrubbed from a RequestResponse trace before it's persisted, so secrets embedded in outgoing webhook
+// requests - API keys, OAuth tokens, session cookies - don't end up stored in the database or shown in
+// the UI.
+type RedactionConfig struct {
+	Headers        []string
+	QueryParams    []string
+	BodyJSONFields []string
+}
+
+// DefaultRedactionConfig returns the RedactionConfig applied to every RequestResponse trace unless
+// Redaction is overridden
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		Headers: []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"},
+	}
+}
+
+// Redaction is the RedactionConfig applied to every RequestResponse trace. Operators can replace it, or
+// append to its Headers, QueryParams and BodyJSONFields, to redact channel-specific secrets.
+var Redaction = DefaultRedactionConfig()
+
+// redact scrubs any headers, query parameters and JSON body fields named in config out of an HTTP
+// request or response dump, replacing their values with redactedValue
+func redact(dump string, config RedactionConfig) string {
+	for _, header := range config.Headers {
+		dump = redactHeader(dump, header)
+	}
+	for _, param := range config.QueryParams {
+		dump = redactQueryParam(dump, param)
+	}
+	for _, field := range config.BodyJSONFields {
+		dump = redactJSONField(dump, field)
+	}
+	return dump
+}
+
+// redactURL returns rawURL with the values of any of params in its query string replaced by
+// redactedValue (the query string is then re-encoded in sorted key order), for storing in
+// RequestResponse.URL without leaking secrets that were passed as query parameters
+func redactURL(rawURL string, params []string) string {
+	if len(params) == 0 {
+		return rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := parsed.Query()
+	changed := false
+	for _, param := range params {
+		if _, found := query[param]; found {
+			query.Set(param, redactedValue)
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func redactHeader(dump, header string) string {
+	// match the value only up to the CRLF the dump uses as a line terminator - a plain ".*$" would also
+	// consume the trailing "\r", leaving that line "\n"-terminated while the rest of the dump stays
+	// "\r\n"-terminated
+	pattern := regexp.MustCompile(`(?im)^(` + regexp.QuoteMeta(header) + `:[ \t]*)[^\r\n]*`)
+	return pattern.ReplaceAllString(dump, "${1}"+redactedValue)
+}
+
+func redactQueryParam(dump, param string) string {
+	pattern := regexp.MustCompile(`(?i)([?&]` + regexp.QuoteMeta(param) + `=)[^&\s]*`)
+	return pattern.ReplaceAllString(dump, "${1}"+redactedValue)
+}
+
+func redactJSONField(dump, field string) string {
+	stringValue := regexp.MustCompile(`(?i)("` + regexp.QuoteMeta(field) + `"\s*:\s*)"[^"]*"`)
+	dump = stringValue.ReplaceAllString(dump, `${1}"`+redactedValue+`"`)
+
+	numericValue := regexp.MustCompile(`(?i)("` + regexp.QuoteMeta(field) + `"\s*:\s*)[0-9.]+`)
+	return numericValue.ReplaceAllString(dump, `${1}"`+redactedValue+`"`)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactHeaderPreservesCRLF(t *testing.T) {
+	dump := "Authorization: Bearer abc123\r\nContent-Type: application/json\r\n\r\n"
+	expected := "Authorization: ********\r\nContent-Type: application/json\r\n\r\n"
+
+	got := redactHeader(dump, "Authorization")
+	if got != expected {
+		t.Errorf("redactHeader() = %q, want %q", got, expected)
+	}
+}
+
+func TestRedactHeaderCaseInsensitive(t *testing.T) {
+	dump := "authorization: Bearer abc123\r\n"
+	expected := "authorization: ********\r\n"
+
+	got := redactHeader(dump, "Authorization")
+	if got != expected {
+		t.Errorf("redactHeader() = %q, want %q", got, expected)
+	}
+}
+
+func TestRedactQueryParam(t *testing.T) {
+	dump := "GET /webhook?token=sekrit&id=123 HTTP/1.1\r\n"
+	expected := "GET /webhook?token=********&id=123 HTTP/1.1\r\n"
+
+	got := redactQueryParam(dump, "token")
+	if got != expected {
+		t.Errorf("redactQueryParam() = %q, want %q", got, expected)
+	}
+}
+
+func TestRedactJSONField(t *testing.T) {
+	cases := []struct {
+		dump     string
+		field    string
+		expected string
+	}{
+		{`{"api_key": "sekrit", "id": 1}`, "api_key", `{"api_key": "********", "id": 1}`},
+		{`{"pin": 1234}`, "pin", `{"pin": "********"}`},
+	}
+
+	for _, c := range cases {
+		got := redactJSONField(c.dump, c.field)
+		if got != c.expected {
+			t.Errorf("redactJSONField(%q, %q) = %q, want %q", c.dump, c.field, got, c.expected)
+		}
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	got := redactURL("https://example.com/hook?token=sekrit&id=1", []string{"token"})
+	expected := "https://example.com/hook?id=1&token=%2A%2A%2A%2A%2A%2A%2A%2A"
+	if got != expected {
+		t.Errorf("redactURL() = %q, want %q", got, expected)
+	}
+
+	unchanged := "https://example.com/hook?id=1"
+	if got := redactURL(unchanged, []string{"token"}); got != unchanged {
+		t.Errorf("redactURL() = %q, want unchanged %q", got, unchanged)
+	}
+}
+
+func TestRedactDefaultConfigCoversSensitiveHeaders(t *testing.T) {
+	dump := "Authorization: Bearer abc\r\nCookie: session=abc\r\nSet-Cookie: session=abc\r\n" +
+		"Proxy-Authorization: Basic abc\r\nX-Api-Key: abc\r\nContent-Type: text/plain\r\n\r\nbody"
+
+	redacted := redact(dump, DefaultRedactionConfig())
+
+	for _, header := range DefaultRedactionConfig().Headers {
+		if !strings.Contains(redacted, header+": "+redactedValue) {
+			t.Errorf("expected %s to be redacted in %q", header, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "Content-Type: text/plain") {
+		t.Errorf("expected unredacted header to survive, got %q", redacted)
+	}
+}
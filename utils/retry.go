@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how MakeHTTPRequestWithRetries retries a failed request
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the request will be attempted, including the first try
+	MaxAttempts int
+
+	// BaseDelay is the upper bound of the jittered delay before the first retry, doubled on each
+	// subsequent attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed backoff delay, regardless of attempt count
+	MaxDelay time.Duration
+
+	// RetryStatusCodes are the response status codes that should trigger a retry
+	RetryStatusCodes map[int]bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suitable for retrying webhook requests against flaky endpoints
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+		RetryStatusCodes: map[int]bool{
+			http.StatusRequestTimeout:     true,
+			http.StatusTooManyRequests:    true,
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+	}
+}
+
+// MakeHTTPRequestWithRetries fires the passed in http request, retrying according to policy on connection
+// failures and on any response whose status code is present in policy.RetryStatusCodes. A Retry-After
+// header on a retryable response takes precedence over the computed backoff delay. The returned
+// RequestResponse concatenates the trace of every attempt made, separated by "--- retry N ---" markers,
+// so operators can see why a webhook endpoint was ultimately considered flaky.
+func MakeHTTPRequestWithRetries(req *http.Request, policy RetryPolicy) (*RequestResponse, error) {
+	return NewHTTPClient(policy).Do(req)
+}
+
+// MakeInsecureHTTPRequestWithRetries is the insecure-transport equivalent of MakeHTTPRequestWithRetries
+func MakeInsecureHTTPRequestWithRetries(req *http.Request, policy RetryPolicy) (*RequestResponse, error) {
+	return NewInsecureHTTPClient(policy).Do(req)
+}
+
+func makeHTTPRequestWithRetries(req *http.Request, policy RetryPolicy, client *http.Client) (*RequestResponse, error) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	// buffer the body so we can replay it on each attempt
+	var bodyBytes []byte
+	if req.Body != nil {
+		var err error
+		bodyBytes, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			rr, _ := newRRFromRequestAndError(req, "", err)
+			return rr, err
+		}
+	}
+
+	var rr *RequestResponse
+	var err error
+
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if bodyBytes != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+			req.ContentLength = int64(len(bodyBytes))
+		}
+
+		start := time.Now()
+		requestTrace, dumpErr := httputil.DumpRequestOut(req, true)
+		if dumpErr != nil {
+			dumpRR, _ := newRRFromRequestAndError(req, string(requestTrace), dumpErr)
+			return mergeAttempt(rr, dumpRR, attempt), dumpErr
+		}
+
+		resp, doErr := client.Do(req)
+
+		var attemptRR *RequestResponse
+		if doErr != nil {
+			attemptRR, _ = newRRFromRequestAndError(req, string(requestTrace), doErr)
+			err = doErr
+		} else {
+			attemptRR, err = newRRFromResponse(string(requestTrace), resp)
+			resp.Body.Close()
+		}
+		attemptRR.Elapsed = time.Now().Sub(start)
+
+		rr = mergeAttempt(rr, attemptRR, attempt)
+
+		retryable := doErr != nil || policy.RetryStatusCodes[attemptRR.StatusCode]
+		if !retryable || attempt == policy.MaxAttempts {
+			return rr, err
+		}
+
+		select {
+		case <-req.Context().Done():
+			return rr, req.Context().Err()
+		case <-time.After(retryDelay(policy, attempt, resp)):
+		}
+	}
+
+	return rr, err
+}
+
+// mergeAttempt folds a single attempt's trace into the running RequestResponse, marking attempts after
+// the first so operators can tell them apart in the combined trace
+func mergeAttempt(rr *RequestResponse, attemptRR *RequestResponse, attempt int) *RequestResponse {
+	if rr == nil {
+		return attemptRR
+	}
+
+	marker := "\n--- retry " + strconv.Itoa(attempt-1) + " ---\n"
+	rr.Request += marker + attemptRR.Request
+	rr.Response += marker + attemptRR.Response
+	rr.Status = attemptRR.Status
+	rr.StatusCode = attemptRR.StatusCode
+	rr.Body = attemptRR.Body
+	rr.Truncated = attemptRR.Truncated
+	rr.Elapsed += attemptRR.Elapsed
+	return rr
+}
+
+// retryDelay computes how long to wait before the next attempt, honoring a Retry-After header on resp
+// when present, and otherwise using exponential backoff with full jitter
+func retryDelay(policy RetryPolicy, attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := retryAfterDelay(resp.Header.Get("Retry-After")); ok {
+			if delay > policy.MaxDelay {
+				return policy.MaxDelay
+			}
+			return delay
+		}
+	}
+
+	backoff := float64(policy.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if max := float64(policy.MaxDelay); backoff > max {
+		backoff = max
+	}
+
+	// full jitter: sleep somewhere between 0 and the computed backoff
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryAfterDelay parses a Retry-After header value, which per RFC 7231 is either a number of seconds or
+// an HTTP-date
+func retryAfterDelay(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		delay := time.Until(when)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterDelaySeconds(t *testing.T) {
+	delay, ok := retryAfterDelay("120")
+	if !ok || delay != 120*time.Second {
+		t.Errorf("retryAfterDelay(\"120\") = %v, %v; want 120s, true", delay, ok)
+	}
+}
+
+func TestRetryAfterDelayHTTPDate(t *testing.T) {
+	when := time.Now().Add(90 * time.Second).UTC()
+	delay, ok := retryAfterDelay(when.Format(http.TimeFormat))
+	if !ok {
+		t.Fatalf("retryAfterDelay(%q) returned ok=false", when.Format(http.TimeFormat))
+	}
+	if delay < 85*time.Second || delay > 90*time.Second {
+		t.Errorf("retryAfterDelay(%q) = %v, want ~90s", when.Format(http.TimeFormat), delay)
+	}
+}
+
+func TestRetryAfterDelayInvalid(t *testing.T) {
+	for _, header := range []string{"", "not-a-number-or-date", "-5"} {
+		if _, ok := retryAfterDelay(header); ok {
+			t.Errorf("retryAfterDelay(%q) returned ok=true, want false", header)
+		}
+	}
+}
+
+func TestRetryDelayBackoffIsBoundedAndCapped(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := retryDelay(policy, attempt, nil)
+		if delay < 0 || delay > policy.MaxDelay {
+			t.Errorf("retryDelay(attempt=%d) = %v, want within [0, %v]", attempt, delay, policy.MaxDelay)
+		}
+	}
+}
+
+func TestRetryDelayHonorsRetryAfterHeader(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"10"}}}
+
+	delay := retryDelay(policy, 1, resp)
+	if delay != 10*time.Second {
+		t.Errorf("retryDelay() = %v, want 10s", delay)
+	}
+}
+
+func TestMakeHTTPRequestWithRetriesRetriesOnRetryableStatus(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	policy := RetryPolicy{
+		MaxAttempts:      3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         10 * time.Millisecond,
+		RetryStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+	}
+
+	rr, err := MakeHTTPRequestWithRetries(req, policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rr.StatusCode != http.StatusOK {
+		t.Errorf("rr.StatusCode = %d, want 200", rr.StatusCode)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestMakeHTTPRequestWithRetriesRespectsContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req = req.WithContext(ctx)
+
+	policy := RetryPolicy{
+		MaxAttempts:      5,
+		BaseDelay:        time.Minute,
+		MaxDelay:         time.Minute,
+		RetryStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := MakeHTTPRequestWithRetries(req, policy)
+	if err == nil {
+		t.Fatal("expected an error from a cancelled context, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("MakeHTTPRequestWithRetries took %v, want it to abort shortly after cancellation", elapsed)
+	}
+}
+
+func TestMakeHTTPRequestWithRetriesPropagatesTruncationFromWinningAttempt(t *testing.T) {
+	originalMaxBodyBytes := MaxBodyBytes
+	MaxBodyBytes = 4
+	defer func() { MaxBodyBytes = originalMaxBodyBytes }()
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Content-Type", "text/plain")
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("this body is longer than the cap"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	policy := RetryPolicy{
+		MaxAttempts:      2,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         10 * time.Millisecond,
+		RetryStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+	}
+
+	rr, err := MakeHTTPRequestWithRetries(req, policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rr.Truncated {
+		t.Error("rr.Truncated = false, want true for the winning attempt's over-cap body")
+	}
+}
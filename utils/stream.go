@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// MakeHTTPRequestStream fires req and copies the response body to sink as it arrives, instead of
+// buffering the whole thing into the returned RequestResponse. This is for webhook responses that may
+// be large or long-lived, such as text/event-stream, where holding the entire body in memory isn't
+// practical. The returned RequestResponse still carries a bounded trace of the request/response headers
+// and the first MaxBodyBytes of the body, with Truncated set if sink received more than that.
+func MakeHTTPRequestStream(req *http.Request, sink io.Writer) (*RequestResponse, error) {
+	start := time.Now()
+	requestTrace, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return newRRFromRequestAndError(req, string(requestTrace), err)
+	}
+
+	resp, err := GetHTTPClient(req.URL.Host).Do(req)
+	if err != nil {
+		return newRRFromRequestAndError(req, string(requestTrace), err)
+	}
+	defer resp.Body.Close()
+
+	rr := RequestResponse{}
+	rr.URL = redactURL(resp.Request.URL.String(), Redaction.QueryParams)
+	rr.StatusCode = resp.StatusCode
+	if rr.StatusCode/100 == 2 {
+		rr.Status = RRStatusSuccess
+	} else {
+		rr.Status = RRStatusFailure
+	}
+	rr.Request = redact(string(requestTrace), Redaction)
+
+	headerDump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return &rr, err
+	}
+	rr.Response = redact(string(headerDump), Redaction)
+
+	trace := &boundedBuffer{limit: MaxBodyBytes}
+	_, err = io.Copy(io.MultiWriter(sink, trace), resp.Body)
+	rr.Elapsed = time.Now().Sub(start)
+	redactedBody := redact(trace.buf.String(), Redaction)
+	rr.Body = []byte(redactedBody)
+	rr.Truncated = trace.truncated
+	rr.Response += redactedBody
+	if err != nil {
+		return &rr, err
+	}
+
+	if rr.Status != RRStatusSuccess {
+		err = fmt.Errorf("received non 200 status: %d", rr.StatusCode)
+	}
+
+	return &rr, err
+}
+
+// boundedBuffer records up to limit bytes written to it and discards the rest, so it can be used
+// alongside a caller's sink in an io.MultiWriter to capture a bounded trace of a streamed body
+type boundedBuffer struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - int64(b.buf.Len()); remaining > 0 {
+		if int64(len(p)) > remaining {
+			b.buf.Write(p[:remaining])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+
+	return len(p), nil
+}
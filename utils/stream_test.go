@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBoundedBufferCapsAtLimit(t *testing.T) {
+	buf := &boundedBuffer{limit: 4}
+
+	buf.Write([]byte("ab"))
+	buf.Write([]byte("cdef"))
+
+	if buf.buf.String() != "abcd" {
+		t.Errorf("buf.buf.String() = %q, want %q", buf.buf.String(), "abcd")
+	}
+	if !buf.truncated {
+		t.Error("buf.truncated = false, want true")
+	}
+}
+
+func TestBoundedBufferUnderLimitNotTruncated(t *testing.T) {
+	buf := &boundedBuffer{limit: 10}
+
+	buf.Write([]byte("abcd"))
+
+	if buf.buf.String() != "abcd" {
+		t.Errorf("buf.buf.String() = %q, want %q", buf.buf.String(), "abcd")
+	}
+	if buf.truncated {
+		t.Error("buf.truncated = true, want false")
+	}
+}
+
+func TestMakeHTTPRequestStreamCopiesFullBodyToSink(t *testing.T) {
+	originalMaxBodyBytes := MaxBodyBytes
+	MaxBodyBytes = 4
+	defer func() { MaxBodyBytes = originalMaxBodyBytes }()
+
+	body := "this body is longer than the trace cap"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	var sink bytes.Buffer
+	rr, err := MakeHTTPRequestStream(req, &sink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sink.String() != body {
+		t.Errorf("sink received %q, want the full body %q", sink.String(), body)
+	}
+	if !rr.Truncated {
+		t.Error("rr.Truncated = false, want true for a body over MaxBodyBytes")
+	}
+	if len(rr.Body) != int(MaxBodyBytes) {
+		t.Errorf("len(rr.Body) = %d, want %d", len(rr.Body), MaxBodyBytes)
+	}
+}